@@ -0,0 +1,62 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysql
+
+// ConnectionParams describes how to connect to a MySQL server, whether as a
+// normal client or as a replication slave registering with a master.
+type ConnectionParams struct {
+	Host       string
+	Port       int
+	Uname      string
+	Pass       string
+	DbName     string
+	UnixSocket string
+
+	// SslEnabled turns on TLS for this connection. When set, at least
+	// SslCa (or the server's default CA) must be usable for the client to
+	// verify the master's certificate.
+	SslEnabled bool
+	// SslCa is the path to the PEM-encoded CA certificate used to verify
+	// the server's certificate.
+	SslCa string
+	// SslCert is the path to the PEM-encoded client certificate, used for
+	// mutual TLS if the server requires it.
+	SslCert string
+	// SslKey is the path to the PEM-encoded private key matching SslCert.
+	SslKey string
+	// SslVerifyServerCert requires that the server's certificate CN match
+	// the host we connected to, not just chain to a trusted CA.
+	SslVerifyServerCert bool
+}
+
+// EnableSSL reports whether this connection should negotiate TLS.
+func (cp *ConnectionParams) EnableSSL() bool {
+	return cp.SslEnabled
+}
+
+// DialOptions returns the DialOption set that makes a connection opened
+// with these params negotiate TLS the same way everywhere a ConnectionParams
+// is dialed, whether that's an admin query or a replication stream. It's the
+// single source of truth for turning SslEnabled/SslCa/SslCert/SslKey into a
+// CLIENT_SSL-flagged, TLS-configured dial.
+func (cp *ConnectionParams) DialOptions() []DialOption {
+	if !cp.EnableSSL() {
+		return nil
+	}
+	return []DialOption{
+		WithCapabilityFlag(ClientSSLCapabilityFlag),
+		WithTLSConfig(TLSConfig{
+			CA:               cp.SslCa,
+			Cert:             cp.SslCert,
+			Key:              cp.SslKey,
+			VerifyServerCert: cp.SslVerifyServerCert,
+		}),
+	}
+}
+
+// ClientSSLCapabilityFlag is the MySQL protocol CLIENT_SSL capability flag,
+// set during the handshake to request a TLS-wrapped connection before any
+// further packets are exchanged.
+const ClientSSLCapabilityFlag = 1 << 11
@@ -0,0 +1,38 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package proto defines the flavor-independent representation of a raw
+// binlog event, as delivered by a MysqlFlavor's MakeBinlogEvent.
+package proto
+
+import (
+	mproto "github.com/youtube/vitess/go/vt/mysqlctl/proto"
+)
+
+// BinlogEvent is a wrapper around a single raw packet read from a
+// replication stream. Flavor-specific implementations know how to pull
+// apart the common header and type-specific body of the packet they wrap.
+type BinlogEvent interface {
+	// IsValid returns true if the underlying packet is long enough to
+	// contain a binlog event header.
+	IsValid() bool
+
+	// Timestamp returns the seconds-since-epoch this event was created on
+	// the master, as recorded in the common header.
+	Timestamp() uint32
+
+	// Bytes returns the raw packet this event wraps, including any
+	// checksum suffix that has not yet been stripped.
+	Bytes() []byte
+}
+
+// GTIDEvent is implemented by any BinlogEvent that can carry a GTID, so
+// callers like BinlogSyncer can track replication position without caring
+// which flavor produced the event.
+type GTIDEvent interface {
+	// GTID returns the GTID carried by this event, and true if this event
+	// actually is a GTID event (as opposed to some other event type that
+	// merely happens to implement this interface).
+	GTID() (mproto.GTID, bool)
+}
@@ -0,0 +1,130 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	mproto "github.com/youtube/vitess/go/vt/mysqlctl/proto"
+)
+
+// MariaDB-specific event types, assigned in the range MySQL reserves for
+// third-party extensions (see binlog_event.h in the MariaDB source).
+const (
+	mariadbGTIDListEventType = 0xA0
+	mariadbGTIDEventType     = 0xA2
+)
+
+// eventHeaderSize is the length of the common binlog event header that
+// precedes every event's type-specific body: timestamp(4) + type(1) +
+// server_id(4) + event_size(4) + log_pos(4) + flags(2).
+const eventHeaderSize = 19
+
+// mariadbBinlogEvent is a BinlogEvent backed by a raw packet from a MariaDB
+// replication stream, after any trailing checksum has already been
+// stripped by the caller (see SlaveConnection's checksum handling).
+type mariadbBinlogEvent struct {
+	buf []byte
+}
+
+// NewMariadbBinlogEvent wraps a raw binlog stream packet, minus the leading
+// OK byte that COM_BINLOG_DUMP prepends to every packet.
+func NewMariadbBinlogEvent(buf []byte) BinlogEvent {
+	return &mariadbBinlogEvent{buf: buf}
+}
+
+// IsValid implements BinlogEvent.IsValid().
+func (ev *mariadbBinlogEvent) IsValid() bool {
+	return len(ev.buf) >= eventHeaderSize
+}
+
+// Timestamp implements BinlogEvent.Timestamp().
+func (ev *mariadbBinlogEvent) Timestamp() uint32 {
+	return binary.LittleEndian.Uint32(ev.buf[0:4])
+}
+
+// Bytes implements BinlogEvent.Bytes().
+func (ev *mariadbBinlogEvent) Bytes() []byte {
+	return ev.buf
+}
+
+func (ev *mariadbBinlogEvent) eventType() byte {
+	return ev.buf[4]
+}
+
+// IsGTID returns true if this event is a MariaDB GTID event (0xA2), i.e. it
+// marks the start of a transaction and carries that transaction's GTID.
+func (ev *mariadbBinlogEvent) IsGTID() bool {
+	return ev.IsValid() && ev.eventType() == mariadbGTIDEventType
+}
+
+// MariadbGTID decodes a MariaDB GTID event's body into a MariadbGTID. The
+// body layout (after the common header) is: sequence(8) domain_id(4)
+// flags(1). The server ID used to build the full GTID comes from the
+// common header's server_id field, per MariaDB semantics.
+func (ev *mariadbBinlogEvent) MariadbGTID() (mproto.MariadbGTID, error) {
+	if !ev.IsGTID() {
+		return mproto.MariadbGTID{}, fmt.Errorf("not a MariaDB GTID event")
+	}
+	body := ev.buf[eventHeaderSize:]
+	if len(body) < 13 {
+		return mproto.MariadbGTID{}, fmt.Errorf("MariaDB GTID event body too short: %v bytes", len(body))
+	}
+	serverID := binary.LittleEndian.Uint32(ev.buf[5:9])
+	seq := binary.LittleEndian.Uint64(body[0:8])
+	domain := binary.LittleEndian.Uint32(body[8:12])
+	return mproto.MariadbGTID{Domain: domain, Server: serverID, Sequence: seq}, nil
+}
+
+// GTID implements GTIDEvent.GTID(), letting generic code like BinlogSyncer
+// track replication position without needing to know this event is a
+// MariaDB one specifically.
+func (ev *mariadbBinlogEvent) GTID() (mproto.GTID, bool) {
+	if !ev.IsGTID() {
+		return nil, false
+	}
+	gtid, err := ev.MariadbGTID()
+	if err != nil {
+		return nil, false
+	}
+	return gtid, true
+}
+
+// IsGTIDList returns true if this event is a MariaDB GTID List event
+// (0xA0), which a master sends at the start of a stream to describe the
+// state of all domains it knows about.
+func (ev *mariadbBinlogEvent) IsGTIDList() bool {
+	return ev.IsValid() && ev.eventType() == mariadbGTIDListEventType
+}
+
+// GTIDList decodes a MariaDB GTID List event's body into a MariadbGTIDSet.
+// The body starts with a 4-byte count, followed by that many
+// (domain_id(4), server_id(4), seq_no(8)) triples.
+func (ev *mariadbBinlogEvent) GTIDList() (mproto.MariadbGTIDSet, error) {
+	if !ev.IsGTIDList() {
+		return nil, fmt.Errorf("not a MariaDB GTID List event")
+	}
+	body := ev.buf[eventHeaderSize:]
+	if len(body) < 4 {
+		return nil, fmt.Errorf("MariaDB GTID List event body too short: %v bytes", len(body))
+	}
+	count := binary.LittleEndian.Uint32(body[0:4])
+	body = body[4:]
+
+	gtidSet := make(mproto.MariadbGTIDSet, count)
+	for i := uint32(0); i < count; i++ {
+		const entrySize = 16
+		if len(body) < entrySize {
+			return nil, fmt.Errorf("MariaDB GTID List event truncated at entry %d", i)
+		}
+		domain := binary.LittleEndian.Uint32(body[0:4])
+		server := binary.LittleEndian.Uint32(body[4:8])
+		seq := binary.LittleEndian.Uint64(body[8:16])
+		gtidSet[domain] = mproto.MariadbGTID{Domain: domain, Server: server, Sequence: seq}
+		body = body[entrySize:]
+	}
+	return gtidSet, nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+import (
+	"encoding/binary"
+	"testing"
+
+	mproto "github.com/youtube/vitess/go/vt/mysqlctl/proto"
+)
+
+// buildGTIDEventBuf assembles a minimal MariaDB GTID event packet: a
+// 19-byte common header (with the given server_id) followed by a
+// sequence(8) domain_id(4) flags(1) body.
+func buildGTIDEventBuf(serverID uint32, seq uint64, domain uint32) []byte {
+	buf := make([]byte, eventHeaderSize+13)
+	buf[4] = mariadbGTIDEventType
+	binary.LittleEndian.PutUint32(buf[5:9], serverID)
+	binary.LittleEndian.PutUint64(buf[eventHeaderSize:eventHeaderSize+8], seq)
+	binary.LittleEndian.PutUint32(buf[eventHeaderSize+8:eventHeaderSize+12], domain)
+	return buf
+}
+
+func TestMariadbBinlogEventGTIDSatisfiesGTIDEvent(t *testing.T) {
+	buf := buildGTIDEventBuf(5, 100, 2)
+	ev := NewMariadbBinlogEvent(buf)
+
+	gtidEvent, ok := ev.(GTIDEvent)
+	if !ok {
+		t.Fatalf("mariadbBinlogEvent does not implement GTIDEvent")
+	}
+	gtid, ok := gtidEvent.GTID()
+	if !ok {
+		t.Fatalf("GTID() returned ok=false for a GTID event")
+	}
+	want := mproto.MariadbGTID{Domain: 2, Server: 5, Sequence: 100}
+	if gtid != want {
+		t.Errorf("GTID() = %#v, want %#v", gtid, want)
+	}
+}
+
+func TestMariadbBinlogEventGTIDFalseForNonGTIDEvent(t *testing.T) {
+	buf := make([]byte, eventHeaderSize+4)
+	buf[4] = 0x02 // some other event type
+	ev := NewMariadbBinlogEvent(buf)
+
+	gtidEvent, ok := ev.(GTIDEvent)
+	if !ok {
+		t.Fatalf("mariadbBinlogEvent does not implement GTIDEvent")
+	}
+	if _, ok := gtidEvent.GTID(); ok {
+		t.Errorf("GTID() returned ok=true for a non-GTID event")
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// TransactionPayloadEventType is the Transaction_payload_event type code,
+// introduced in MySQL 8.0.20 for binlog_transaction_compression=ON. A
+// flavor's MakeBinlogEvent checks a packet's event type against this
+// constant to decide whether to wrap it with NewTransactionPayloadEvent
+// instead of its own plain event type.
+const TransactionPayloadEventType = 0x28
+
+// Payload header field codes, as defined by MySQL's
+// Transaction_payload_event::read_well_known_fields().
+const (
+	payloadSizeField             = 1
+	payloadCompressionTypeField  = 2
+	payloadUncompressedSizeField = 3
+	payloadTerminatorField       = 0
+)
+
+// Compression type codes carried in the payload header.
+const (
+	compressionZstd = 0
+	compressionNone = 255
+)
+
+// FormatDescription carries the parts of a stream's Format Description
+// Event that are needed to re-frame the sub-events packed inside a
+// TransactionPayloadEvent: the length of the common header shared by every
+// event in the stream.
+type FormatDescription struct {
+	// CommonHeaderLength is the size in bytes of the header that precedes
+	// every event's type-specific body in this stream.
+	CommonHeaderLength int
+}
+
+// TransactionPayloadEvent is a BinlogEvent for a MySQL 8.0
+// Transaction_payload_event, which bundles an entire transaction's worth of
+// events into one (optionally compressed) blob. SubEvents unpacks them.
+type TransactionPayloadEvent interface {
+	BinlogEvent
+
+	// SubEvents decompresses (if needed) and re-frames the events bundled
+	// inside this Transaction_payload_event, propagating server_id and
+	// log_pos from the outer event to each one, per MySQL semantics.
+	SubEvents() ([]BinlogEvent, error)
+}
+
+// transactionPayloadEvent is a BinlogEvent for a MySQL 8.0
+// Transaction_payload_event, which bundles an entire transaction's worth of
+// events into one (optionally compressed) blob.
+type transactionPayloadEvent struct {
+	buf       []byte
+	serverID  uint32
+	logPos    uint32
+	fde       FormatDescription
+	makeEvent func([]byte) BinlogEvent
+}
+
+// NewTransactionPayloadEvent wraps a raw Transaction_payload_event packet.
+// serverID and logPos come from the enclosing event's common header, and
+// are propagated to each decoded sub-event per MySQL semantics. fde
+// describes the enclosing stream's Format Description Event, needed to
+// re-frame sub-events after decompression. makeEvent is the flavor's own
+// MakeBinlogEvent, used to turn each re-framed sub-event's raw bytes into a
+// BinlogEvent exactly like a top-level one.
+func NewTransactionPayloadEvent(buf []byte, serverID, logPos uint32, fde FormatDescription, makeEvent func([]byte) BinlogEvent) TransactionPayloadEvent {
+	return &transactionPayloadEvent{buf: buf, serverID: serverID, logPos: logPos, fde: fde, makeEvent: makeEvent}
+}
+
+// IsValid implements BinlogEvent.IsValid().
+func (ev *transactionPayloadEvent) IsValid() bool {
+	return len(ev.buf) >= eventHeaderSize && ev.buf[4] == TransactionPayloadEventType
+}
+
+// Timestamp implements BinlogEvent.Timestamp().
+func (ev *transactionPayloadEvent) Timestamp() uint32 {
+	return binary.LittleEndian.Uint32(ev.buf[0:4])
+}
+
+// Bytes implements BinlogEvent.Bytes().
+func (ev *transactionPayloadEvent) Bytes() []byte {
+	return ev.buf
+}
+
+// payloadHeader is the result of parsing the TLV fields that precede a
+// Transaction_payload_event's compressed (or plain) body.
+type payloadHeader struct {
+	compressionType     uint64
+	uncompressedSize    uint64
+	uncompressedSizeSet bool
+}
+
+// parsePayloadHeader reads the TLV-encoded fields at the start of body,
+// stopping at the terminator field, and returns the header plus the
+// remaining bytes (the actual payload).
+func parsePayloadHeader(body []byte) (payloadHeader, []byte, error) {
+	var header payloadHeader
+	r := bytes.NewReader(body)
+	for {
+		field, err := binary.ReadUvarint(r)
+		if err != nil {
+			return header, nil, fmt.Errorf("truncated transaction payload header: %v", err)
+		}
+		if field == payloadTerminatorField {
+			break
+		}
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return header, nil, fmt.Errorf("truncated transaction payload header: %v", err)
+		}
+
+		switch field {
+		case payloadCompressionTypeField:
+			header.compressionType, err = readUvarintField(r, length)
+		case payloadUncompressedSizeField:
+			header.uncompressedSize, err = readUvarintField(r, length)
+			header.uncompressedSizeSet = true
+		case payloadSizeField:
+			// The on-the-wire payload size is implicit in what's left of
+			// body once we're done with the header, so we don't need to
+			// track it separately; just skip over it.
+			_, err = io.CopyN(ioutil.Discard, r, int64(length))
+		default:
+			_, err = io.CopyN(ioutil.Discard, r, int64(length))
+		}
+		if err != nil {
+			return header, nil, fmt.Errorf("truncated transaction payload field %d: %v", field, err)
+		}
+	}
+
+	payload := body[len(body)-r.Len():]
+	return header, payload, nil
+}
+
+func readUvarintField(r *bytes.Reader, length uint64) (uint64, error) {
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	reader := bytes.NewReader(buf)
+	v, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// SubEvents implements TransactionPayloadEvent.SubEvents().
+func (ev *transactionPayloadEvent) SubEvents() ([]BinlogEvent, error) {
+	body := ev.buf[eventHeaderSize:]
+	header, payload, err := parsePayloadHeader(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var decompressed []byte
+	switch header.compressionType {
+	case compressionNone:
+		decompressed = payload
+	case compressionZstd:
+		if !header.uncompressedSizeSet || header.uncompressedSize == 0 {
+			return nil, fmt.Errorf("transaction payload header is missing OTW_PAYLOAD_UNCOMPRESSED_SIZE_FIELD, can't decompress")
+		}
+		decoder, err := zstd.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("can't create zstd reader for transaction payload: %v", err)
+		}
+		defer decoder.Close()
+
+		buf := make([]byte, 0, header.uncompressedSize)
+		out := bytes.NewBuffer(buf)
+		if _, err := io.CopyN(out, decoder, int64(header.uncompressedSize)); err != nil {
+			return nil, fmt.Errorf("can't decompress transaction payload: %v", err)
+		}
+		decompressed = out.Bytes()
+	default:
+		return nil, fmt.Errorf("unsupported transaction payload compression type %d", header.compressionType)
+	}
+
+	return ev.splitSubEvents(decompressed)
+}
+
+// splitSubEvents walks the decompressed buffer, re-framing one sub-event at
+// a time using its common header's event_size field, and rejects any
+// sub-event that is itself a Transaction_payload_event (MySQL disallows
+// nesting).
+func (ev *transactionPayloadEvent) splitSubEvents(buf []byte) ([]BinlogEvent, error) {
+	var events []BinlogEvent
+	for len(buf) > 0 {
+		if len(buf) < ev.fde.CommonHeaderLength {
+			return nil, fmt.Errorf("truncated sub-event header: %d bytes left, want %d", len(buf), ev.fde.CommonHeaderLength)
+		}
+		eventSize := binary.LittleEndian.Uint32(buf[9:13])
+		if int(eventSize) < ev.fde.CommonHeaderLength {
+			return nil, fmt.Errorf("sub-event claims size %d, too short to hold a %d-byte common header", eventSize, ev.fde.CommonHeaderLength)
+		}
+		if int(eventSize) > len(buf) {
+			return nil, fmt.Errorf("sub-event claims size %d but only %d bytes remain", eventSize, len(buf))
+		}
+
+		raw := buf[:eventSize]
+		if raw[4] == TransactionPayloadEventType {
+			return nil, fmt.Errorf("nested Transaction_payload_event is not allowed")
+		}
+
+		// Propagate server_id and log_pos from the outer event, per MySQL
+		// semantics for events unpacked from a compressed transaction.
+		binary.LittleEndian.PutUint32(raw[5:9], ev.serverID)
+		binary.LittleEndian.PutUint32(raw[13:17], ev.logPos)
+
+		events = append(events, ev.makeEvent(raw))
+		buf = buf[eventSize:]
+	}
+	return events, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildSubEvent constructs a raw sub-event packet: a 19-byte common header
+// (server_id and log_pos left as placeholders for the caller under test to
+// overwrite) followed by a 1-byte body, with event_size set correctly.
+func buildSubEvent(eventType byte, body byte) []byte {
+	const size = eventHeaderSize + 1
+	buf := make([]byte, size)
+	buf[4] = eventType
+	binary.LittleEndian.PutUint32(buf[9:13], uint32(size))
+	buf[eventHeaderSize] = body
+	return buf
+}
+
+// uvarintField TLV-encodes a (field, length, value) triple the way
+// parsePayloadHeader expects to read it back.
+func uvarintField(field, value uint64) []byte {
+	var buf bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(tmp[:], field)
+	buf.Write(tmp[:n])
+
+	var valBuf bytes.Buffer
+	vn := binary.PutUvarint(tmp[:], value)
+	valBuf.Write(tmp[:vn])
+
+	n = binary.PutUvarint(tmp[:], uint64(valBuf.Len()))
+	buf.Write(tmp[:n])
+	buf.Write(valBuf.Bytes())
+	return buf.Bytes()
+}
+
+func TestParsePayloadHeaderUncompressed(t *testing.T) {
+	sub1 := buildSubEvent(0x02, 0xAA)
+	sub2 := buildSubEvent(0x02, 0xBB)
+	payload := append(append([]byte{}, sub1...), sub2...)
+
+	var body bytes.Buffer
+	body.Write(uvarintField(payloadCompressionTypeField, compressionNone))
+	body.WriteByte(payloadTerminatorField)
+	body.Write(payload)
+
+	header, got, err := parsePayloadHeader(body.Bytes())
+	if err != nil {
+		t.Fatalf("parsePayloadHeader: %v", err)
+	}
+	if header.compressionType != compressionNone {
+		t.Errorf("compressionType = %d, want %d", header.compressionType, compressionNone)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("payload = %v, want %v", got, payload)
+	}
+}
+
+func TestTransactionPayloadEventSubEventsUncompressed(t *testing.T) {
+	sub1 := buildSubEvent(0x02, 0xAA)
+	sub2 := buildSubEvent(0x02, 0xBB)
+	subEvents := append(append([]byte{}, sub1...), sub2...)
+
+	var body bytes.Buffer
+	body.Write(uvarintField(payloadCompressionTypeField, compressionNone))
+	body.WriteByte(payloadTerminatorField)
+	body.Write(subEvents)
+
+	outerHeader := make([]byte, eventHeaderSize)
+	outerHeader[4] = TransactionPayloadEventType
+	buf := append(outerHeader, body.Bytes()...)
+
+	var decoded [][]byte
+	makeEvent := func(raw []byte) BinlogEvent {
+		decoded = append(decoded, raw)
+		return NewMariadbBinlogEvent(raw)
+	}
+
+	ev := NewTransactionPayloadEvent(buf, 42, 1000, FormatDescription{CommonHeaderLength: eventHeaderSize}, makeEvent)
+	events, err := ev.SubEvents()
+	if err != nil {
+		t.Fatalf("SubEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("SubEvents returned %d events, want 2", len(events))
+	}
+	for i, raw := range decoded {
+		gotServerID := binary.LittleEndian.Uint32(raw[5:9])
+		gotLogPos := binary.LittleEndian.Uint32(raw[13:17])
+		if gotServerID != 42 {
+			t.Errorf("sub-event %d server_id = %d, want 42", i, gotServerID)
+		}
+		if gotLogPos != 1000 {
+			t.Errorf("sub-event %d log_pos = %d, want 1000", i, gotLogPos)
+		}
+	}
+}
+
+func TestTransactionPayloadEventSubEventsRejectsUndersizedSubEvent(t *testing.T) {
+	// A sub-event claiming an event_size smaller than the common header
+	// (19 bytes) must be rejected before splitSubEvents tries to read
+	// raw[4]/raw[5:9]/raw[13:17] out of it.
+	undersized := make([]byte, eventHeaderSize)
+	binary.LittleEndian.PutUint32(undersized[9:13], 10)
+
+	var body bytes.Buffer
+	body.Write(uvarintField(payloadCompressionTypeField, compressionNone))
+	body.WriteByte(payloadTerminatorField)
+	body.Write(undersized)
+
+	outerHeader := make([]byte, eventHeaderSize)
+	outerHeader[4] = TransactionPayloadEventType
+	buf := append(outerHeader, body.Bytes()...)
+
+	ev := NewTransactionPayloadEvent(buf, 1, 1, FormatDescription{CommonHeaderLength: eventHeaderSize}, NewMariadbBinlogEvent)
+	if _, err := ev.SubEvents(); err == nil {
+		t.Error("SubEvents with an undersized sub-event succeeded, want an error")
+	}
+}
+
+func TestTransactionPayloadEventSubEventsRejectsMissingUncompressedSize(t *testing.T) {
+	var body bytes.Buffer
+	body.Write(uvarintField(payloadCompressionTypeField, compressionZstd))
+	body.WriteByte(payloadTerminatorField)
+	body.Write([]byte{0x01, 0x02, 0x03}) // not valid zstd, but we should fail before reading it
+
+	outerHeader := make([]byte, eventHeaderSize)
+	outerHeader[4] = TransactionPayloadEventType
+	buf := append(outerHeader, body.Bytes()...)
+
+	ev := NewTransactionPayloadEvent(buf, 1, 1, FormatDescription{CommonHeaderLength: eventHeaderSize}, NewMariadbBinlogEvent)
+	if _, err := ev.SubEvents(); err == nil {
+		t.Error("SubEvents with no uncompressed-size field succeeded, want an error")
+	}
+}
+
+func TestTransactionPayloadEventSubEventsRejectsNesting(t *testing.T) {
+	nested := buildSubEvent(TransactionPayloadEventType, 0x00)
+
+	var body bytes.Buffer
+	body.Write(uvarintField(payloadCompressionTypeField, compressionNone))
+	body.WriteByte(payloadTerminatorField)
+	body.Write(nested)
+
+	outerHeader := make([]byte, eventHeaderSize)
+	outerHeader[4] = TransactionPayloadEventType
+	buf := append(outerHeader, body.Bytes()...)
+
+	ev := NewTransactionPayloadEvent(buf, 1, 1, FormatDescription{CommonHeaderLength: eventHeaderSize}, NewMariadbBinlogEvent)
+	if _, err := ev.SubEvents(); err == nil {
+		t.Error("SubEvents with a nested Transaction_payload_event succeeded, want an error")
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	log "github.com/golang/glog"
+	blproto "github.com/youtube/vitess/go/vt/binlog/proto"
+	"github.com/youtube/vitess/go/vt/mysqlctl/proto"
+	"golang.org/x/net/context"
+)
+
+// BinlogSyncerConfig holds the parameters needed to open and maintain a
+// replication stream from a master, independent of whether the caller wants
+// to resume from a file/offset or a GTID position.
+type BinlogSyncerConfig struct {
+	// ServerID is the replication slave ID this syncer registers as.
+	ServerID uint32
+	// Flavor selects the MysqlFlavor used to interpret GTIDs and events
+	// (e.g. "MariaDB" or "GoogleMysql"). Defaults to the process-wide flavor
+	// if empty.
+	Flavor string
+
+	// Host, Port, User and Password are not used to connect: every
+	// connection is dialed from the Params of the Mysqld passed to
+	// NewBinlogSyncer.
+	Host     string
+	Port     int
+	User     string
+	Password string
+
+	// MaxReconnectAttempts bounds how many times the syncer will retry a
+	// dropped connection before giving up and returning an error from
+	// GetEvent. Zero means retry forever.
+	MaxReconnectAttempts int
+
+	// HeartbeatPeriod tells the master how often to send a heartbeat event
+	// when there's no real traffic, so the syncer can tell the difference
+	// between an idle connection and a dead one.
+	HeartbeatPeriod time.Duration
+
+	// SemiSyncEnabled requests semi-sync acknowledgement of each event.
+	SemiSyncEnabled bool
+}
+
+// BinlogSyncer manages a replication connection to a master, transparently
+// reconnecting on transient network errors and resuming from the last GTID
+// it delivered to the caller.
+type BinlogSyncer struct {
+	cfg    BinlogSyncerConfig
+	mysqld *Mysqld
+	flavor MysqlFlavor
+}
+
+// Position identifies a point in a master's binlog by file name and byte
+// offset, for use with StartSync's classic (non-GTID) replication mode.
+type Position struct {
+	File string
+	Pos  uint32
+}
+
+// Streamer is the consumer-facing handle returned by StartSync/StartSyncGTID.
+// Events are delivered over a buffered channel so the syncer's reconnect loop
+// can keep making progress while the caller is busy processing the previous
+// event.
+type Streamer struct {
+	ctx    context.Context
+	events chan blproto.BinlogEvent
+	errors chan error
+	cancel context.CancelFunc
+}
+
+// GetEvent blocks until the next binlog event is available, the streamer's
+// underlying context is cancelled, or the caller's ctx is done.
+func (s *Streamer) GetEvent(ctx context.Context) (blproto.BinlogEvent, error) {
+	select {
+	case ev, ok := <-s.events:
+		if !ok {
+			return nil, fmt.Errorf("binlog stream closed")
+		}
+		return ev, nil
+	case err := <-s.errors:
+		return nil, err
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// NewBinlogSyncer creates a BinlogSyncer using the given config. It does not
+// connect to the master until StartSync or StartSyncGTID is called.
+func NewBinlogSyncer(mysqld *Mysqld, cfg BinlogSyncerConfig) (*BinlogSyncer, error) {
+	flavor := mysqlFlavor()
+	if cfg.Flavor != "" {
+		f, ok := mysqlFlavors[cfg.Flavor]
+		if !ok {
+			return nil, fmt.Errorf("unknown MySQL flavor %q", cfg.Flavor)
+		}
+		flavor = f
+	}
+	return &BinlogSyncer{cfg: cfg, mysqld: mysqld, flavor: flavor}, nil
+}
+
+// StartSync begins streaming from the given file/position using classic
+// (non-GTID) replication. The returned Streamer delivers events until the
+// caller cancels ctx or calls Close.
+func (s *BinlogSyncer) StartSync(ctx context.Context, startPos Position) (*Streamer, error) {
+	streamer := s.newStreamer(ctx)
+	go s.runFilePos(streamer.ctx, startPos, streamer)
+	return streamer, nil
+}
+
+// StartSyncGTID begins streaming starting just after the given replication
+// position, expressed as a flavor-specific GTID set.
+func (s *BinlogSyncer) StartSyncGTID(ctx context.Context, startPos proto.ReplicationPosition) (*Streamer, error) {
+	streamer := s.newStreamer(ctx)
+	go s.runGTID(streamer.ctx, startPos, streamer)
+	return streamer, nil
+}
+
+func (s *BinlogSyncer) newStreamer(ctx context.Context) *Streamer {
+	streamCtx, cancel := context.WithCancel(ctx)
+	return &Streamer{
+		ctx:    streamCtx,
+		events: make(chan blproto.BinlogEvent, 1000),
+		errors: make(chan error, 1),
+		cancel: cancel,
+	}
+}
+
+// Close stops the streamer's reconnect loop and releases its connection.
+func (s *Streamer) Close() {
+	s.cancel()
+}
+
+// runGTID owns the reconnect loop for StartSyncGTID: it (re)opens a
+// SlaveConnection, asks the flavor to register and resume from pos via
+// GTID, and pumps events into the streamer's channel until ctx is done or
+// MaxReconnectAttempts is exceeded. On every event that carries a GTID, pos
+// is advanced so a reconnect resumes just past the last event delivered
+// rather than replaying from the original start position.
+func (s *BinlogSyncer) runGTID(ctx context.Context, pos proto.ReplicationPosition, streamer *Streamer) {
+	s.run(ctx, streamer, func(conn *SlaveConnection) error {
+		return s.flavor.SendBinlogDumpCommand(s.mysqld, conn, pos)
+	}, func(ev blproto.BinlogEvent) {
+		pos = advanceGTIDPos(pos, ev)
+	}, func() string {
+		return pos.String()
+	})
+}
+
+// advanceGTIDPos returns pos extended by ev's GTID, if ev carries one, or
+// pos unchanged otherwise. This is what lets a reconnect resume just past
+// the last event delivered instead of replaying from the original start
+// position.
+func advanceGTIDPos(pos proto.ReplicationPosition, ev blproto.BinlogEvent) proto.ReplicationPosition {
+	gtidEvent, hasGTID := ev.(blproto.GTIDEvent)
+	if !hasGTID {
+		return pos
+	}
+	gtid, ok := gtidEvent.GTID()
+	if !ok {
+		return pos
+	}
+	return proto.AppendGTID(pos, gtid)
+}
+
+// runFilePos owns the reconnect loop for StartSync: it (re)opens a
+// SlaveConnection and issues the classic COM_BINLOG_DUMP command to resume
+// from a file/offset, advancing pos.Pos from each event's log_pos header
+// field so a reconnect resumes from the last position delivered.
+func (s *BinlogSyncer) runFilePos(ctx context.Context, pos Position, streamer *Streamer) {
+	s.run(ctx, streamer, func(conn *SlaveConnection) error {
+		if err := conn.negotiateChecksum(s.flavor.ChecksumAlgorithm()); err != nil {
+			return err
+		}
+		return conn.WriteComBinlogDump(conn.slaveID, pos.File, pos.Pos, 0)
+	}, func(ev blproto.BinlogEvent) {
+		buf := ev.Bytes()
+		if len(buf) >= eventHeaderLogPosEnd {
+			pos.Pos = binary.LittleEndian.Uint32(buf[eventHeaderLogPosStart:eventHeaderLogPosEnd])
+		}
+	}, func() string {
+		return fmt.Sprintf("%s:%d", pos.File, pos.Pos)
+	})
+}
+
+// eventHeaderLogPosStart/End bound the log_pos field within the common
+// binlog event header: timestamp(4) type(1) server_id(4) event_size(4)
+// log_pos(4) flags(2).
+const (
+	eventHeaderLogPosStart = 13
+	eventHeaderLogPosEnd   = 17
+)
+
+// run is the reconnect loop shared by runGTID and runFilePos. register is
+// called on every (re)connection, after connect has set up the heartbeat
+// period and semi-sync, to negotiate checksums and issue the appropriate
+// COM_BINLOG_DUMP variant. observe is called with every
+// event read, so the caller can advance its own notion of "pos" before the
+// event is handed to the consumer. describePos is used only for logging.
+func (s *BinlogSyncer) run(ctx context.Context, streamer *Streamer, register func(*SlaveConnection) error, observe func(blproto.BinlogEvent), describePos func() string) {
+	attempts := 0
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		conn, err := s.connect(ctx, register)
+		if err != nil {
+			attempts++
+			if s.cfg.MaxReconnectAttempts > 0 && attempts > s.cfg.MaxReconnectAttempts {
+				s.deliverError(streamer, fmt.Errorf("binlog syncer giving up after %d attempts: %v", attempts, err))
+				return
+			}
+			log.Warningf("binlog syncer: connect failed (attempt %d): %v", attempts, err)
+			if !sleepOrDone(ctx, reconnectBackoff(attempts)) {
+				return
+			}
+			continue
+		}
+		attempts = 0
+
+		for {
+			buf, err := conn.ReadPacket()
+			if err != nil {
+				conn.Close()
+				log.Warningf("binlog syncer: lost connection, will resume from %v: %v", describePos(), err)
+				break
+			}
+			ev := s.flavor.MakeBinlogEvent(buf)
+			observe(ev)
+			select {
+			case streamer.events <- ev:
+			case <-ctx.Done():
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// connect opens a fresh SlaveConnection honoring cfg.ServerID, configures
+// the heartbeat period and semi-sync, and calls register to issue whichever
+// flavor/mode-specific COM_BINLOG_DUMP variant the caller needs.
+func (s *BinlogSyncer) connect(ctx context.Context, register func(*SlaveConnection) error) (*SlaveConnection, error) {
+	conn, err := NewSlaveConnection(s.mysqld, s.cfg.ServerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cfg.HeartbeatPeriod > 0 {
+		nanos := s.cfg.HeartbeatPeriod.Nanoseconds()
+		if _, err := conn.ExecuteFetch(fmt.Sprintf("SET @master_heartbeat_period=%d", nanos), 0, false); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set @master_heartbeat_period: %v", err)
+		}
+	}
+
+	if s.cfg.SemiSyncEnabled {
+		if err := conn.EnableSemiSync(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if err := register(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (s *BinlogSyncer) deliverError(streamer *Streamer, err error) {
+	select {
+	case streamer.errors <- err:
+	default:
+	}
+}
+
+// reconnectBackoff returns an increasing delay between reconnect attempts,
+// capped at 30 seconds so a flapping network doesn't turn into a hot loop.
+func reconnectBackoff(attempt int) time.Duration {
+	backoff := time.Duration(attempt) * time.Second
+	if backoff > 30*time.Second {
+		backoff = 30 * time.Second
+	}
+	return backoff
+}
+
+// sleepOrDone waits for d, returning false early if ctx is cancelled first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
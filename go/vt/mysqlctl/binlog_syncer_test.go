@@ -0,0 +1,77 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"encoding/binary"
+	"testing"
+
+	blproto "github.com/youtube/vitess/go/vt/binlog/proto"
+	"github.com/youtube/vitess/go/vt/mysqlctl/proto"
+)
+
+// gtidEventBuf builds a minimal MariaDB GTID event packet carrying
+// domain/sequence, for feeding through blproto.NewMariadbBinlogEvent the
+// same way the real flavor's MakeBinlogEvent would.
+func gtidEventBuf(serverID uint32, domain uint32, seq uint64) []byte {
+	const (
+		headerSize       = 19
+		mariadbGTIDEvent = 0xA2
+	)
+	buf := make([]byte, headerSize+13)
+	buf[4] = mariadbGTIDEvent
+	binary.LittleEndian.PutUint32(buf[5:9], serverID)
+	binary.LittleEndian.PutUint64(buf[headerSize:headerSize+8], seq)
+	binary.LittleEndian.PutUint32(buf[headerSize+8:headerSize+12], domain)
+	return buf
+}
+
+// TestRunGTIDResumesFromLastDeliveredPosition reproduces the reconnect
+// scenario from runGTID: a stream of GTID events advances pos as they're
+// delivered, a reconnect happens partway through, and the resumed position
+// reflects only the events actually delivered before the drop — not the
+// original start position and not events that were never delivered.
+func TestRunGTIDResumesFromLastDeliveredPosition(t *testing.T) {
+	pos := proto.ReplicationPosition{}
+
+	delivered := []blproto.BinlogEvent{
+		blproto.NewMariadbBinlogEvent(gtidEventBuf(1, 0, 10)),
+		blproto.NewMariadbBinlogEvent(gtidEventBuf(1, 0, 11)),
+	}
+	for _, ev := range delivered {
+		pos = advanceGTIDPos(pos, ev)
+	}
+
+	// Simulate a reconnect here: runGTID would now call
+	// flavor.SendBinlogDumpCommand with this resumed pos rather than the
+	// original (zero-value) start position.
+	resumed := pos
+	if resumed.IsZero() {
+		t.Fatalf("resumed position is zero-value, want it advanced past seq 11")
+	}
+
+	notYetDelivered := blproto.NewMariadbBinlogEvent(gtidEventBuf(1, 0, 12))
+	after := advanceGTIDPos(resumed, notYetDelivered)
+
+	want, err := proto.ParseMariadbGTIDSet("0-1-12")
+	if err != nil {
+		t.Fatalf("ParseMariadbGTIDSet: %v", err)
+	}
+	if after.String() != want.String() {
+		t.Errorf("final position = %v, want %v", after, want)
+	}
+	if resumed.String() == after.String() {
+		t.Errorf("resumed position %v should not already include the not-yet-delivered event", resumed)
+	}
+}
+
+func TestAdvanceGTIDPosIgnoresNonGTIDEvents(t *testing.T) {
+	pos := proto.ReplicationPosition{}
+	nonGTID := blproto.NewMariadbBinlogEvent(make([]byte, 23))
+	got := advanceGTIDPos(pos, nonGTID)
+	if !got.IsZero() {
+		t.Errorf("advanceGTIDPos advanced past a non-GTID event: %v", got)
+	}
+}
@@ -53,6 +53,13 @@ type MysqlFlavor interface {
 
 	// WaitMasterPos waits until slave replication reaches at least targetPos.
 	WaitMasterPos(mysqld *Mysqld, targetPos proto.ReplicationPosition, waitTimeout time.Duration) error
+
+	// ChecksumAlgorithm returns the binlog checksum algorithm this flavor
+	// should negotiate with the master during slave registration, e.g.
+	// "NONE" or "CRC32". SendBinlogDumpCommand is expected to advertise
+	// this value via SET @master_binlog_checksum before issuing
+	// COM_BINLOG_DUMP.
+	ChecksumAlgorithm() string
 }
 
 var mysqlFlavors map[string]MysqlFlavor = make(map[string]MysqlFlavor)
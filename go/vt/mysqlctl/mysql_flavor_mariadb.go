@@ -0,0 +1,204 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/youtube/vitess/go/mysql"
+	blproto "github.com/youtube/vitess/go/vt/binlog/proto"
+	"github.com/youtube/vitess/go/vt/mysqlctl/proto"
+)
+
+// mariaDB10Flavor implements the MysqlFlavor interface for MariaDB 10.0.2
+// and above, which replaced file/position replication with its own global
+// transaction ID scheme (see https://mariadb.com/kb/en/gtid/).
+type mariaDB10Flavor struct{}
+
+// MasterPosition implements MysqlFlavor.MasterPosition().
+//
+// On MariaDB, the current position is read from @@gtid_binlog_pos, which
+// reflects every GTID the master has written to its own binlog (as opposed
+// to @@gtid_current_pos, which also includes GTIDs replicated from a
+// master but not yet logged).
+func (*mariaDB10Flavor) MasterPosition(mysqld *Mysqld) (proto.ReplicationPosition, error) {
+	qr, err := mysqld.fetchSuperQuery("SELECT @@GLOBAL.gtid_binlog_pos")
+	if err != nil {
+		return proto.ReplicationPosition{}, err
+	}
+	if len(qr.Rows) != 1 {
+		return proto.ReplicationPosition{}, fmt.Errorf("unexpected result format for gtid_binlog_pos: %#v", qr)
+	}
+	return parseMariadbGTIDReplicationPosition(qr.Rows[0][0].String())
+}
+
+// SlaveStatus implements MysqlFlavor.SlaveStatus().
+func (*mariaDB10Flavor) SlaveStatus(mysqld *Mysqld) (*proto.ReplicationStatus, error) {
+	fields, err := mysqld.slaveStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &proto.ReplicationStatus{
+		SlaveIORunning:     fields["Slave_IO_Running"] == "Yes",
+		SlaveSQLRunning:    fields["Slave_SQL_Running"] == "Yes",
+		MasterHost:         fields["Master_Host"],
+		MasterPort:         atoiDefault(fields["Master_Port"]),
+		MasterConnectRetry: atoiDefault(fields["Connect_Retry"]),
+	}
+	pos, err := parseMariadbGTIDReplicationPosition(fields["Gtid_Slave_Pos"])
+	if err != nil {
+		return nil, fmt.Errorf("SlaveStatus: can't parse gtid_slave_pos %q: %v", fields["Gtid_Slave_Pos"], err)
+	}
+	status.Position = pos
+	return status, nil
+}
+
+// PromoteSlaveCommands implements MysqlFlavor.PromoteSlaveCommands().
+func (*mariaDB10Flavor) PromoteSlaveCommands() []string {
+	return []string{
+		"STOP SLAVE",
+		"RESET SLAVE ALL",
+	}
+}
+
+// StartReplicationCommands implements MysqlFlavor.StartReplicationCommands().
+func (*mariaDB10Flavor) StartReplicationCommands(params *mysql.ConnectionParams, status *proto.ReplicationStatus) ([]string, error) {
+	changeMaster := fmt.Sprintf("CHANGE MASTER TO MASTER_HOST='%s', MASTER_PORT=%d, MASTER_USER='%s', MASTER_PASSWORD='%s', master_use_gtid=slave_pos",
+		params.Host, params.Port, params.Uname, params.Pass)
+	changeMaster += sslChangeMasterClause(params)
+
+	return []string{
+		fmt.Sprintf("SET GLOBAL gtid_slave_pos = '%s'", status.Position.String()),
+		changeMaster,
+		"START SLAVE",
+	}, nil
+}
+
+// sslChangeMasterClause returns the MASTER_SSL=... suffix to append to a
+// CHANGE MASTER TO statement when params requests a TLS-protected
+// replication connection, or "" otherwise.
+func sslChangeMasterClause(params *mysql.ConnectionParams) string {
+	if !params.EnableSSL() {
+		return ""
+	}
+	clause := ", MASTER_SSL=1"
+	if params.SslCa != "" {
+		clause += fmt.Sprintf(", MASTER_SSL_CA='%s'", params.SslCa)
+	}
+	if params.SslCert != "" {
+		clause += fmt.Sprintf(", MASTER_SSL_CERT='%s'", params.SslCert)
+	}
+	if params.SslKey != "" {
+		clause += fmt.Sprintf(", MASTER_SSL_KEY='%s'", params.SslKey)
+	}
+	if params.SslVerifyServerCert {
+		clause += ", MASTER_SSL_VERIFY_SERVER_CERT=1"
+	}
+	return clause
+}
+
+// ParseGTID implements MysqlFlavor.ParseGTID().
+func (*mariaDB10Flavor) ParseGTID(s string) (proto.GTID, error) {
+	return proto.ParseMariadbGTID(s)
+}
+
+// ParseReplicationPosition implements MysqlFlavor.ParseReplicationPosition().
+func (*mariaDB10Flavor) ParseReplicationPosition(s string) (proto.ReplicationPosition, error) {
+	return parseMariadbGTIDReplicationPosition(s)
+}
+
+// SendBinlogDumpCommand implements MysqlFlavor.SendBinlogDumpCommand().
+//
+// MariaDB requires a slave to announce its GTID capabilities before issuing
+// COM_BINLOG_DUMP, so the master knows it's safe to resume from a GTID
+// position rather than a file/offset pair.
+func (f *mariaDB10Flavor) SendBinlogDumpCommand(mysqld *Mysqld, conn *SlaveConnection, startPos proto.ReplicationPosition) error {
+	if err := conn.negotiateChecksum(f.ChecksumAlgorithm()); err != nil {
+		return err
+	}
+	if _, err := conn.ExecuteFetch("SET @mariadb_slave_capability=4", 0, false); err != nil {
+		return fmt.Errorf("failed to set @mariadb_slave_capability: %v", err)
+	}
+	if _, err := conn.ExecuteFetch(fmt.Sprintf("SET @slave_connect_state='%s'", startPos.String()), 0, false); err != nil {
+		return fmt.Errorf("failed to set @slave_connect_state: %v", err)
+	}
+	if _, err := conn.ExecuteFetch("SET @slave_gtid_strict_mode=1", 0, false); err != nil {
+		return fmt.Errorf("failed to set @slave_gtid_strict_mode: %v", err)
+	}
+	if _, err := conn.ExecuteFetch("SET @slave_gtid_ignore_duplicates=0", 0, false); err != nil {
+		return fmt.Errorf("failed to set @slave_gtid_ignore_duplicates: %v", err)
+	}
+
+	return conn.WriteComBinlogDump(conn.slaveID, "", 0, 0)
+}
+
+// mariadbCommonHeaderLength is the size of the common header MariaDB uses
+// ahead of every event's type-specific body, including the events packed
+// inside a Transaction_payload_event (MariaDB does not vary this by binlog
+// version in practice).
+const mariadbCommonHeaderLength = 19
+
+// MakeBinlogEvent implements MysqlFlavor.MakeBinlogEvent().
+//
+// buf is expected to already have any negotiated checksum stripped by the
+// SlaveConnection that read it (see SlaveConnection.ReadPacket). A
+// Transaction_payload_event (MySQL 8.0 compressed transaction, type 0x28)
+// is wrapped so its SubEvents() re-decodes its contents through this same
+// MakeBinlogEvent, rather than as a plain, unreadable MariaDB event.
+func (f *mariaDB10Flavor) MakeBinlogEvent(buf []byte) blproto.BinlogEvent {
+	if len(buf) >= mariadbCommonHeaderLength && buf[4] == blproto.TransactionPayloadEventType {
+		serverID := binary.LittleEndian.Uint32(buf[5:9])
+		logPos := binary.LittleEndian.Uint32(buf[13:17])
+		fde := blproto.FormatDescription{CommonHeaderLength: mariadbCommonHeaderLength}
+		return blproto.NewTransactionPayloadEvent(buf, serverID, logPos, fde, f.MakeBinlogEvent)
+	}
+	return blproto.NewMariadbBinlogEvent(buf)
+}
+
+// ChecksumAlgorithm implements MysqlFlavor.ChecksumAlgorithm().
+//
+// MariaDB defaults binlog_checksum to CRC32 as of 10.0, so we always
+// advertise and verify it; DisableChecksum on the SlaveConnection is
+// available for masters that don't actually send one.
+func (*mariaDB10Flavor) ChecksumAlgorithm() string {
+	return "CRC32"
+}
+
+// WaitMasterPos implements MysqlFlavor.WaitMasterPos().
+//
+// MariaDB provides MASTER_GTID_WAIT(gtid, timeout), which blocks until the
+// slave's gtid_slave_pos includes gtid or the timeout (in seconds) elapses.
+func (*mariaDB10Flavor) WaitMasterPos(mysqld *Mysqld, targetPos proto.ReplicationPosition, waitTimeout time.Duration) error {
+	query := fmt.Sprintf("SELECT MASTER_GTID_WAIT('%s', %.6f)", targetPos.String(), waitTimeout.Seconds())
+	qr, err := mysqld.fetchSuperQuery(query)
+	if err != nil {
+		return fmt.Errorf("MASTER_GTID_WAIT() failed: %v", err)
+	}
+	if len(qr.Rows) != 1 || qr.Rows[0][0].String() != "0" {
+		return fmt.Errorf("timed out waiting for position %v", targetPos)
+	}
+	return nil
+}
+
+func parseMariadbGTIDReplicationPosition(s string) (proto.ReplicationPosition, error) {
+	gtidSet, err := proto.ParseMariadbGTIDSet(s)
+	if err != nil {
+		return proto.ReplicationPosition{}, fmt.Errorf("can't parse MariaDB GTID set %q: %v", s, err)
+	}
+	return proto.ReplicationPosition{GTIDSet: gtidSet}, nil
+}
+
+func atoiDefault(s string) int {
+	var n int
+	fmt.Sscanf(s, "%d", &n)
+	return n
+}
+
+func init() {
+	mysqlFlavors["MariaDB"] = &mariaDB10Flavor{}
+}
@@ -0,0 +1,57 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/youtube/vitess/go/mysql"
+	blproto "github.com/youtube/vitess/go/vt/binlog/proto"
+)
+
+func TestSslChangeMasterClauseNoSSL(t *testing.T) {
+	params := &mysql.ConnectionParams{}
+	if got := sslChangeMasterClause(params); got != "" {
+		t.Errorf("sslChangeMasterClause() = %q, want empty string", got)
+	}
+}
+
+func TestSslChangeMasterClauseWithSSL(t *testing.T) {
+	params := &mysql.ConnectionParams{
+		SslEnabled:          true,
+		SslCa:               "/etc/ca.pem",
+		SslCert:             "/etc/cert.pem",
+		SslKey:              "/etc/key.pem",
+		SslVerifyServerCert: true,
+	}
+	got := sslChangeMasterClause(params)
+
+	for _, want := range []string{
+		"MASTER_SSL=1",
+		"MASTER_SSL_CA='/etc/ca.pem'",
+		"MASTER_SSL_CERT='/etc/cert.pem'",
+		"MASTER_SSL_KEY='/etc/key.pem'",
+		"MASTER_SSL_VERIFY_SERVER_CERT=1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sslChangeMasterClause() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestMakeBinlogEventRejectsTruncatedTransactionPayloadEvent(t *testing.T) {
+	flavor := &mariaDB10Flavor{}
+	for _, size := range []int{0, 5, 16, 18} {
+		buf := make([]byte, size)
+		if size > 4 {
+			buf[4] = blproto.TransactionPayloadEventType
+		}
+		ev := flavor.MakeBinlogEvent(buf)
+		if ev.IsValid() {
+			t.Errorf("MakeBinlogEvent(%d-byte truncated 0x28 packet).IsValid() = true, want false", size)
+		}
+	}
+}
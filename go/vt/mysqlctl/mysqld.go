@@ -0,0 +1,75 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/youtube/vitess/go/mysql"
+	"github.com/youtube/vitess/go/sqltypes"
+)
+
+// ErrNotSlave is returned by slaveStatus when SHOW SLAVE STATUS comes back
+// empty, meaning this instance isn't configured as a replication slave.
+var ErrNotSlave = errors.New("no slave status: not configured as a replication slave")
+
+// Mysqld wraps the connection parameters and control operations for a single
+// mysqld instance. MysqlFlavor implementations use it to run the
+// flavor-specific queries that make up replication control.
+type Mysqld struct {
+	// Params is used to open new connections to this instance, including
+	// the ones used to register as a replication slave.
+	Params mysql.ConnectionParams
+}
+
+// NewMysqld creates a Mysqld for the instance reachable with params.
+//
+// If --replication_ssl_ca is set, ApplyReplicationSSLFlags enables SSL on
+// params here, so both ExecuteFetch and every SlaveConnection opened from
+// this Mysqld (its only two ways of dialing out) pick it up automatically.
+func NewMysqld(params mysql.ConnectionParams) *Mysqld {
+	ApplyReplicationSSLFlags(&params)
+	return &Mysqld{Params: params}
+}
+
+// fetchSuperQuery runs a single-row administrative query (e.g. SELECT
+// @@GLOBAL.some_var) and returns the raw result.
+func (mysqld *Mysqld) fetchSuperQuery(query string) (*sqltypes.Result, error) {
+	return mysqld.ExecuteFetch(query, 1, true)
+}
+
+// ExecuteFetch runs query against this instance using a fresh connection,
+// returning at most maxrows rows.
+//
+// It dials through the same ConnectionParams.DialOptions() path as
+// SlaveConnection, so admin queries negotiate TLS the same way the
+// replication stream does whenever Params has SSL enabled.
+func (mysqld *Mysqld) ExecuteFetch(query string, maxrows int, wantFields bool) (*sqltypes.Result, error) {
+	conn, err := mysql.DialWithOptions(mysqld.Params, mysqld.Params.DialOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ExecuteFetch(query, maxrows, wantFields)
+}
+
+// slaveStatus runs SHOW SLAVE STATUS and returns the result as a map keyed
+// by column name, since the set of columns varies by flavor.
+func (mysqld *Mysqld) slaveStatus() (map[string]string, error) {
+	qr, err := mysqld.fetchSuperQuery("SHOW SLAVE STATUS")
+	if err != nil {
+		return nil, err
+	}
+	if len(qr.Rows) == 0 {
+		return nil, ErrNotSlave
+	}
+
+	fields := make(map[string]string, len(qr.Fields))
+	for i, field := range qr.Fields {
+		fields[strings.TrimSpace(field.Name)] = qr.Rows[0][i].String()
+	}
+	return fields, nil
+}
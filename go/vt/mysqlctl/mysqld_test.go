@@ -0,0 +1,41 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"testing"
+
+	"github.com/youtube/vitess/go/mysql"
+)
+
+func TestNewMysqldAppliesReplicationSSLFlags(t *testing.T) {
+	oldCa, oldCert, oldKey := *replicationSslCa, *replicationSslCert, *replicationSslKey
+	defer func() {
+		*replicationSslCa, *replicationSslCert, *replicationSslKey = oldCa, oldCert, oldKey
+	}()
+	*replicationSslCa = "/etc/ca.pem"
+	*replicationSslCert = "/etc/cert.pem"
+	*replicationSslKey = "/etc/key.pem"
+
+	mysqld := NewMysqld(mysql.ConnectionParams{Host: "master"})
+
+	if !mysqld.Params.EnableSSL() {
+		t.Fatalf("NewMysqld did not enable SSL despite --replication_ssl_ca being set")
+	}
+	if mysqld.Params.SslCa != "/etc/ca.pem" || mysqld.Params.SslCert != "/etc/cert.pem" || mysqld.Params.SslKey != "/etc/key.pem" {
+		t.Errorf("NewMysqld.Params = %#v, want the replication_ssl_* flags copied in", mysqld.Params)
+	}
+}
+
+func TestNewMysqldLeavesSSLDisabledByDefault(t *testing.T) {
+	oldCa := *replicationSslCa
+	defer func() { *replicationSslCa = oldCa }()
+	*replicationSslCa = ""
+
+	mysqld := NewMysqld(mysql.ConnectionParams{Host: "master"})
+	if mysqld.Params.EnableSSL() {
+		t.Errorf("NewMysqld enabled SSL with no --replication_ssl_ca set")
+	}
+}
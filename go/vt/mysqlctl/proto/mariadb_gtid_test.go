@@ -0,0 +1,87 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+import "testing"
+
+func TestMariadbGTIDRoundTrip(t *testing.T) {
+	want := MariadbGTID{Domain: 0, Server: 62344, Sequence: 1991}
+	gtid, err := ParseMariadbGTID(want.String())
+	if err != nil {
+		t.Fatalf("ParseMariadbGTID(%q): %v", want.String(), err)
+	}
+	if gtid != want {
+		t.Errorf("ParseMariadbGTID(%q) = %#v, want %#v", want.String(), gtid, want)
+	}
+}
+
+func TestParseMariadbGTIDRejectsMalformed(t *testing.T) {
+	for _, s := range []string{"", "1-2", "1-2-3-4", "a-2-3", "1-b-3", "1-2-c"} {
+		if _, err := ParseMariadbGTID(s); err == nil {
+			t.Errorf("ParseMariadbGTID(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestMariadbGTIDSetRoundTrip(t *testing.T) {
+	const s = "0-1-100,1-2-200,2-3-300"
+	gtidSet, err := ParseMariadbGTIDSet(s)
+	if err != nil {
+		t.Fatalf("ParseMariadbGTIDSet(%q): %v", s, err)
+	}
+	if len(gtidSet) != 3 {
+		t.Fatalf("ParseMariadbGTIDSet(%q) has %d domains, want 3", s, len(gtidSet))
+	}
+	if gtidSet.String() != s {
+		t.Errorf("String() = %q, want %q", gtidSet.String(), s)
+	}
+}
+
+func TestParseMariadbGTIDSetKeepsHigherSequenceOnRepeatedDomain(t *testing.T) {
+	gtidSet, err := ParseMariadbGTIDSet("0-1-50,0-1-100")
+	if err != nil {
+		t.Fatalf("ParseMariadbGTIDSet: %v", err)
+	}
+	if len(gtidSet) != 1 {
+		t.Fatalf("ParseMariadbGTIDSet has %d domains, want 1", len(gtidSet))
+	}
+	if gtidSet[0].Sequence != 100 {
+		t.Errorf("domain 0 sequence = %d, want 100 (the higher of the two, regardless of input order)", gtidSet[0].Sequence)
+	}
+
+	// Reversing the input order must not change the result.
+	gtidSet, err = ParseMariadbGTIDSet("0-1-100,0-1-50")
+	if err != nil {
+		t.Fatalf("ParseMariadbGTIDSet: %v", err)
+	}
+	if gtidSet[0].Sequence != 100 {
+		t.Errorf("domain 0 sequence = %d, want 100", gtidSet[0].Sequence)
+	}
+}
+
+func TestMariadbGTIDSetUnionKeepsHigherSequencePerDomain(t *testing.T) {
+	a, _ := ParseMariadbGTIDSet("0-1-100")
+	b, _ := ParseMariadbGTIDSet("0-1-50,1-2-200")
+
+	union := a.Union(b).(MariadbGTIDSet)
+	if union[0].Sequence != 100 {
+		t.Errorf("domain 0 sequence = %d, want 100", union[0].Sequence)
+	}
+	if union[1].Sequence != 200 {
+		t.Errorf("domain 1 sequence = %d, want 200", union[1].Sequence)
+	}
+}
+
+func TestMariadbGTIDSetContains(t *testing.T) {
+	ahead, _ := ParseMariadbGTIDSet("0-1-100,1-2-200")
+	behind, _ := ParseMariadbGTIDSet("0-1-50")
+
+	if !ahead.Contains(behind) {
+		t.Error("ahead.Contains(behind) = false, want true")
+	}
+	if behind.Contains(ahead) {
+		t.Error("behind.Contains(ahead) = true, want false")
+	}
+}
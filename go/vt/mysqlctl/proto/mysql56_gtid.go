@@ -0,0 +1,311 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mysql56GTID implements GTID for the MySQL 5.6+ global transaction ID
+// format, which pairs the UUID of the server that originally committed the
+// transaction with a sequence number unique to that server:
+// "de278ad0-2e5f-11e6-a259-0242ac120002:5".
+type Mysql56GTID struct {
+	// Server is the source server's UUID.
+	Server string
+	// Sequence is the sequence number of the transaction on Server.
+	Sequence uint64
+}
+
+// String implements GTID.String().
+func (gtid Mysql56GTID) String() string {
+	return fmt.Sprintf("%s:%d", gtid.Server, gtid.Sequence)
+}
+
+// ParseMysql56GTID parses a single "uuid:seq" GTID.
+func ParseMysql56GTID(s string) (Mysql56GTID, error) {
+	pos := strings.LastIndex(s, ":")
+	if pos < 0 {
+		return Mysql56GTID{}, fmt.Errorf("invalid MySQL56 GTID %q: expected uuid:seq", s)
+	}
+	seq, err := strconv.ParseUint(s[pos+1:], 10, 64)
+	if err != nil {
+		return Mysql56GTID{}, fmt.Errorf("invalid sequence in MySQL56 GTID %q: %v", s, err)
+	}
+	return Mysql56GTID{Server: s[:pos], Sequence: seq}, nil
+}
+
+// mysql56Interval is an inclusive range of sequence numbers, e.g. "5-7"
+// covers sequence numbers 5, 6 and 7.
+type mysql56Interval struct {
+	start, end uint64
+}
+
+func (iv mysql56Interval) String() string {
+	if iv.start == iv.end {
+		return strconv.FormatUint(iv.start, 10)
+	}
+	return fmt.Sprintf("%d-%d", iv.start, iv.end)
+}
+
+// Mysql56GTIDSet implements GTIDSet for a MySQL 5.6+ GTID set, which tracks
+// a list of non-overlapping, non-adjacent sequence intervals per source
+// server UUID, e.g. "uuid1:1-3:5-7,uuid2:1-5".
+type Mysql56GTIDSet map[string][]mysql56Interval
+
+// String implements GTIDSet.String(). Servers are sorted for determinism;
+// each server's intervals come out merged and in ascending order.
+func (gtidSet Mysql56GTIDSet) String() string {
+	servers := make([]string, 0, len(gtidSet))
+	for server := range gtidSet {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	parts := make([]string, 0, len(servers))
+	for _, server := range servers {
+		intervalStrs := make([]string, len(gtidSet[server]))
+		for i, iv := range gtidSet[server] {
+			intervalStrs[i] = iv.String()
+		}
+		parts = append(parts, server+":"+strings.Join(intervalStrs, ":"))
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseMysql56GTIDSet parses a MySQL 5.6+ GTID set of the form
+// "uuid1:1-3:5-7,uuid2:1-5" into a Mysql56GTIDSet.
+func ParseMysql56GTIDSet(s string) (Mysql56GTIDSet, error) {
+	gtidSet := make(Mysql56GTIDSet)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return gtidSet, nil
+	}
+
+	for _, serverSet := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(serverSet), ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid MySQL56 GTID set %q: expected uuid:interval[:interval...]", serverSet)
+		}
+		server := parts[0]
+
+		var intervals []mysql56Interval
+		for _, intervalStr := range parts[1:] {
+			iv, err := parseMysql56Interval(intervalStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid MySQL56 GTID set %q: %v", serverSet, err)
+			}
+			intervals = mergeInterval(intervals, iv)
+		}
+		gtidSet[server] = intervals
+	}
+	return gtidSet, nil
+}
+
+func parseMysql56Interval(s string) (mysql56Interval, error) {
+	parts := strings.Split(s, "-")
+	start, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return mysql56Interval{}, fmt.Errorf("invalid interval %q: %v", s, err)
+	}
+	if len(parts) == 1 {
+		return mysql56Interval{start: start, end: start}, nil
+	}
+	if len(parts) != 2 {
+		return mysql56Interval{}, fmt.Errorf("invalid interval %q", s)
+	}
+	end, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return mysql56Interval{}, fmt.Errorf("invalid interval %q: %v", s, err)
+	}
+	return mysql56Interval{start: start, end: end}, nil
+}
+
+// mergeInterval inserts iv into the sorted, merged interval list intervals,
+// combining it with any interval it overlaps or is adjacent to (e.g. 1-3
+// and 4-7 merge into 1-7, since there's no sequence number gap between
+// them).
+func mergeInterval(intervals []mysql56Interval, iv mysql56Interval) []mysql56Interval {
+	merged := append(append([]mysql56Interval{}, intervals...), iv)
+	return normalizeIntervals(merged)
+}
+
+// normalizeIntervals sorts a list of intervals by start and merges any that
+// overlap or are adjacent.
+func normalizeIntervals(intervals []mysql56Interval) []mysql56Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start < intervals[j].start
+	})
+
+	result := []mysql56Interval{intervals[0]}
+	for _, iv := range intervals[1:] {
+		last := &result[len(result)-1]
+		if iv.start > last.end+1 {
+			result = append(result, iv)
+			continue
+		}
+		if iv.end > last.end {
+			last.end = iv.end
+		}
+	}
+	return result
+}
+
+// intersectIntervals returns the overlap between two sorted, merged
+// interval lists.
+func intersectIntervals(a, b []mysql56Interval) []mysql56Interval {
+	var result []mysql56Interval
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		start := maxUint64(a[i].start, b[j].start)
+		end := minUint64(a[i].end, b[j].end)
+		if start <= end {
+			result = append(result, mysql56Interval{start: start, end: end})
+		}
+		if a[i].end < b[j].end {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// subtractIntervals returns the parts of a's intervals not covered by any
+// interval in b.
+func subtractIntervals(a, b []mysql56Interval) []mysql56Interval {
+	var result []mysql56Interval
+	for _, iv := range a {
+		remaining := []mysql56Interval{iv}
+		for _, sub := range b {
+			var next []mysql56Interval
+			for _, r := range remaining {
+				if sub.end < r.start || sub.start > r.end {
+					next = append(next, r)
+					continue
+				}
+				if sub.start > r.start {
+					next = append(next, mysql56Interval{start: r.start, end: sub.start - 1})
+				}
+				if sub.end < r.end {
+					next = append(next, mysql56Interval{start: sub.end + 1, end: r.end})
+				}
+			}
+			remaining = next
+		}
+		result = append(result, remaining...)
+	}
+	return normalizeIntervals(result)
+}
+
+// containsIntervals returns true if every sequence number in b is covered
+// by some interval in a.
+func containsIntervals(a, b []mysql56Interval) bool {
+	for _, ivB := range b {
+		covered := false
+		for _, ivA := range a {
+			if ivA.start <= ivB.start && ivB.end <= ivA.end {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Union implements GTIDSet.Union().
+func (gtidSet Mysql56GTIDSet) Union(other GTIDSet) GTIDSet {
+	otherSet, ok := other.(Mysql56GTIDSet)
+	if !ok {
+		return gtidSet
+	}
+
+	union := make(Mysql56GTIDSet, len(gtidSet))
+	for server, intervals := range gtidSet {
+		union[server] = append([]mysql56Interval{}, intervals...)
+	}
+	for server, intervals := range otherSet {
+		combined := append(union[server], intervals...)
+		union[server] = normalizeIntervals(combined)
+	}
+	return union
+}
+
+// Intersect implements GTIDSet.Intersect().
+func (gtidSet Mysql56GTIDSet) Intersect(other GTIDSet) GTIDSet {
+	otherSet, ok := other.(Mysql56GTIDSet)
+	if !ok {
+		return Mysql56GTIDSet{}
+	}
+
+	intersection := make(Mysql56GTIDSet)
+	for server, intervals := range gtidSet {
+		otherIntervals, ok := otherSet[server]
+		if !ok {
+			continue
+		}
+		if result := intersectIntervals(intervals, otherIntervals); len(result) > 0 {
+			intersection[server] = result
+		}
+	}
+	return intersection
+}
+
+// Subtract implements GTIDSet.Subtract().
+func (gtidSet Mysql56GTIDSet) Subtract(other GTIDSet) GTIDSet {
+	otherSet, ok := other.(Mysql56GTIDSet)
+	if !ok {
+		return gtidSet
+	}
+
+	diff := make(Mysql56GTIDSet)
+	for server, intervals := range gtidSet {
+		result := subtractIntervals(intervals, otherSet[server])
+		if len(result) > 0 {
+			diff[server] = result
+		}
+	}
+	return diff
+}
+
+// Contains implements GTIDSet.Contains(). This set contains other if every
+// sequence number other has observed, for every server, is also covered by
+// this set.
+func (gtidSet Mysql56GTIDSet) Contains(other GTIDSet) bool {
+	otherSet, ok := other.(Mysql56GTIDSet)
+	if !ok {
+		return false
+	}
+
+	for server, otherIntervals := range otherSet {
+		if !containsIntervals(gtidSet[server], otherIntervals) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+import "testing"
+
+func TestMysql56GTIDSetUnionMergesAdjacentIntervals(t *testing.T) {
+	a, err := ParseMysql56GTIDSet("uuid1:1-3")
+	if err != nil {
+		t.Fatalf("ParseMysql56GTIDSet: %v", err)
+	}
+	b, err := ParseMysql56GTIDSet("uuid1:4-7")
+	if err != nil {
+		t.Fatalf("ParseMysql56GTIDSet: %v", err)
+	}
+
+	got := a.Union(b).String()
+	want := "uuid1:1-7"
+	if got != want {
+		t.Errorf("Union(1-3, 4-7) = %q, want %q", got, want)
+	}
+}
+
+func TestMysql56GTIDSetUnionMergesOverlappingIntervals(t *testing.T) {
+	a, err := ParseMysql56GTIDSet("uuid1:1-5")
+	if err != nil {
+		t.Fatalf("ParseMysql56GTIDSet: %v", err)
+	}
+	b, err := ParseMysql56GTIDSet("uuid1:3-9")
+	if err != nil {
+		t.Fatalf("ParseMysql56GTIDSet: %v", err)
+	}
+
+	got := a.Union(b).String()
+	want := "uuid1:1-9"
+	if got != want {
+		t.Errorf("Union(1-5, 3-9) = %q, want %q", got, want)
+	}
+}
+
+func TestMysql56GTIDSetIntersectSubtractContains(t *testing.T) {
+	a, err := ParseMysql56GTIDSet("uuid1:1-10")
+	if err != nil {
+		t.Fatalf("ParseMysql56GTIDSet: %v", err)
+	}
+	b, err := ParseMysql56GTIDSet("uuid1:5-20")
+	if err != nil {
+		t.Fatalf("ParseMysql56GTIDSet: %v", err)
+	}
+
+	if got, want := a.Intersect(b).String(), "uuid1:5-10"; got != want {
+		t.Errorf("Intersect = %q, want %q", got, want)
+	}
+	if got, want := a.Subtract(b).String(), "uuid1:1-4"; got != want {
+		t.Errorf("Subtract = %q, want %q", got, want)
+	}
+	if a.Contains(b) {
+		t.Errorf("a.Contains(b) = true, want false (b extends past a)")
+	}
+	if !a.Contains(a) {
+		t.Errorf("a.Contains(a) = false, want true")
+	}
+}
+
+func TestMysql56GTIDRoundTrip(t *testing.T) {
+	const s = "de278ad0-2e5f-11e6-a259-0242ac120002:12345"
+	gtid, err := ParseMysql56GTID(s)
+	if err != nil {
+		t.Fatalf("ParseMysql56GTID(%q): %v", s, err)
+	}
+	if got := gtid.String(); got != s {
+		t.Errorf("String() = %q, want %q", got, s)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto
+
+// GTID is a vitess-defined interface for a single global transaction ID, as
+// used by one of the MySQL flavors. A GTID implementation is specific to
+// one flavor (e.g. MariadbGTID), but every flavor's GTID can be printed in
+// its own canonical form.
+type GTID interface {
+	// String returns the canonical printed form of the GTID as expected by
+	// a flavor-specific variable like gtid_slave_pos.
+	String() string
+}
+
+// GTIDSet is a vitess-defined interface for a set of GTIDs observed on a
+// given server, as used by one of the MySQL flavors. Flavor-specific
+// implementations (e.g. MariadbGTIDSet) know how to merge and compare sets
+// in the way their flavor represents them, e.g. by interval-merging
+// sequence ranges, or by tracking the max sequence number per replication
+// domain.
+type GTIDSet interface {
+	// String returns the canonical form of this set as expected by a
+	// flavor-specific variable like gtid_slave_pos.
+	String() string
+
+	// Union returns the set of GTIDs observed in either set.
+	Union(other GTIDSet) GTIDSet
+	// Intersect returns the set of GTIDs observed in both sets.
+	Intersect(other GTIDSet) GTIDSet
+	// Subtract returns the GTIDs in this set that are not also in other.
+	Subtract(other GTIDSet) GTIDSet
+	// Contains returns true if every GTID in other has also been applied
+	// to this set.
+	Contains(other GTIDSet) bool
+}
+
+// ReplicationPosition represents the state of replication on a server, as a
+// set of GTIDs in whatever form the server's MySQL flavor uses.
+type ReplicationPosition struct {
+	GTIDSet GTIDSet
+}
+
+// String returns the canonical form of the underlying GTID set, or "" if
+// this position is the zero value.
+func (rp ReplicationPosition) String() string {
+	if rp.GTIDSet == nil {
+		return ""
+	}
+	return rp.GTIDSet.String()
+}
+
+// IsZero returns true if this position doesn't carry a GTID set, e.g.
+// because it was never successfully parsed.
+func (rp ReplicationPosition) IsZero() bool {
+	return rp.GTIDSet == nil
+}
+
+// Union returns a ReplicationPosition covering every GTID in either rp or
+// other.
+func (rp ReplicationPosition) Union(other ReplicationPosition) ReplicationPosition {
+	return ReplicationPosition{GTIDSet: rp.GTIDSet.Union(other.GTIDSet)}
+}
+
+// Intersect returns a ReplicationPosition covering only the GTIDs common to
+// both rp and other.
+func (rp ReplicationPosition) Intersect(other ReplicationPosition) ReplicationPosition {
+	return ReplicationPosition{GTIDSet: rp.GTIDSet.Intersect(other.GTIDSet)}
+}
+
+// Subtract returns a ReplicationPosition covering the GTIDs in rp that are
+// not also in other.
+func (rp ReplicationPosition) Subtract(other ReplicationPosition) ReplicationPosition {
+	return ReplicationPosition{GTIDSet: rp.GTIDSet.Subtract(other.GTIDSet)}
+}
+
+// Contains returns true if every GTID in other has also been applied to rp,
+// i.e. rp is at least as advanced as other. A zero-value (unparsed/unknown)
+// position is treated as the empty set: it contains only another empty
+// position, and is contained by anything.
+func (rp ReplicationPosition) Contains(other ReplicationPosition) bool {
+	if rp.IsZero() {
+		return other.IsZero()
+	}
+	if other.IsZero() {
+		return true
+	}
+	return rp.GTIDSet.Contains(other.GTIDSet)
+}
+
+// AppendGTID returns a ReplicationPosition that includes gtid in addition
+// to everything already in rp. This is used to track position incrementally
+// as a streamer observes new GTID events, without needing to re-parse the
+// whole replication position from the server on every event.
+//
+// Only a MariadbGTID is appended, and only to a zero-value position or one
+// holding a MariadbGTIDSet; for any other GTID type or set type, rp is
+// returned unchanged.
+func AppendGTID(rp ReplicationPosition, gtid GTID) ReplicationPosition {
+	if rp.GTIDSet == nil {
+		if mgtid, ok := gtid.(MariadbGTID); ok {
+			return ReplicationPosition{GTIDSet: MariadbGTIDSet{mgtid.Domain: mgtid}}
+		}
+		return rp
+	}
+	if mgtid, ok := gtid.(MariadbGTID); ok {
+		if mset, ok := rp.GTIDSet.(MariadbGTIDSet); ok {
+			return ReplicationPosition{GTIDSet: mset.Union(MariadbGTIDSet{mgtid.Domain: mgtid}).(MariadbGTIDSet)}
+		}
+	}
+	return rp
+}
+
+// ReplicationStatus mirrors the fields of SHOW SLAVE STATUS that vitess
+// cares about, normalized across flavors.
+type ReplicationStatus struct {
+	Position           ReplicationPosition
+	SlaveIORunning     bool
+	SlaveSQLRunning    bool
+	MasterHost         string
+	MasterPort         int
+	MasterConnectRetry int
+}
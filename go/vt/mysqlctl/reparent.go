@@ -0,0 +1,77 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"fmt"
+
+	"github.com/youtube/vitess/go/vt/mysqlctl/proto"
+)
+
+// FindMostAdvancedReplicationPosition returns the index in positions of a
+// replica that has applied the most GTIDs, for use by reparent and
+// emergency_reparent when picking which candidate to promote.
+//
+// Unlike a lexicographic comparison of the printed GTID sets (which breaks
+// down as soon as more than one replication domain or server is involved),
+// GTID sets only form a partial order: position[i] not containing
+// position[j] does NOT imply position[j] contains position[i] — they can
+// be incomparable (each has applied transactions the other hasn't), or
+// even behind it in a different domain. So rather than tracking a single
+// running "best" and swapping it out greedily, a candidate is picked only
+// if no other candidate has strictly more applied than it (i.e. it isn't
+// dominated). If multiple candidates are incomparable maximal elements
+// (neither dominates the other), the first one found is returned; callers
+// that need a deterministic tie-break among truly equivalent candidates
+// should pre-sort positions accordingly.
+func FindMostAdvancedReplicationPosition(positions []proto.ReplicationPosition) int {
+	for i := range positions {
+		if !dominatedByAny(positions, i) {
+			return i
+		}
+	}
+	return 0
+}
+
+// dominatedByAny returns true if some other position in positions strictly
+// contains positions[i] (has applied everything positions[i] has, plus
+// more), meaning positions[i] cannot be the most advanced.
+func dominatedByAny(positions []proto.ReplicationPosition, i int) bool {
+	for j := range positions {
+		if j == i {
+			continue
+		}
+		if positions[j].Contains(positions[i]) && !positions[i].Contains(positions[j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmergencyReparentShard picks the most advanced of candidates (by GTIDs
+// applied, via FindMostAdvancedReplicationPosition) and returns its index
+// along with the commands to run on it to promote it to master. It's meant
+// for the case where the current master is unreachable, so there's no
+// authoritative source to copy a position from — the replica that has
+// applied the most is the safest candidate, since promoting anyone less
+// advanced would require the others to roll back transactions they've
+// already applied.
+func EmergencyReparentShard(flavor MysqlFlavor, candidates []*Mysqld) (promotedIndex int, promoteCommands []string, err error) {
+	if len(candidates) == 0 {
+		return 0, nil, fmt.Errorf("EmergencyReparentShard: no candidates to choose from")
+	}
+
+	positions := make([]proto.ReplicationPosition, len(candidates))
+	for i, mysqld := range candidates {
+		status, err := flavor.SlaveStatus(mysqld)
+		if err != nil {
+			return 0, nil, fmt.Errorf("EmergencyReparentShard: can't get replication status for candidate %d: %v", i, err)
+		}
+		positions[i] = status.Position
+	}
+
+	winner := FindMostAdvancedReplicationPosition(positions)
+	return winner, flavor.PromoteSlaveCommands(), nil
+}
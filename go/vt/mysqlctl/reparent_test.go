@@ -0,0 +1,133 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/youtube/vitess/go/mysql"
+	blproto "github.com/youtube/vitess/go/vt/binlog/proto"
+	"github.com/youtube/vitess/go/vt/mysqlctl/proto"
+)
+
+func mustMariadbPosition(t *testing.T, s string) proto.ReplicationPosition {
+	t.Helper()
+	gtidSet, err := proto.ParseMariadbGTIDSet(s)
+	if err != nil {
+		t.Fatalf("ParseMariadbGTIDSet(%q): %v", s, err)
+	}
+	return proto.ReplicationPosition{GTIDSet: gtidSet}
+}
+
+func TestFindMostAdvancedReplicationPositionIncomparable(t *testing.T) {
+	// replica 0 dominates replica 2 (ahead or equal in every domain), but
+	// is incomparable with replica 1 (each has applied transactions in a
+	// domain the other hasn't). A greedy scan that only tracks a single
+	// running "best" and swaps to i whenever best doesn't contain i (e.g.
+	// best=0 -> swap to 1, since 0 doesn't contain 1 -> swap to 2, since 1
+	// doesn't contain 2 either) ends up on replica 2, even though replica
+	// 0 strictly dominates it. The correct answer must never be a replica
+	// that some other replica strictly dominates.
+	positions := []proto.ReplicationPosition{
+		mustMariadbPosition(t, "0-1-10,1-1-10"), // dominates replica 2
+		mustMariadbPosition(t, "2-1-10"),        // incomparable with both
+		mustMariadbPosition(t, "0-1-5,1-1-5"),   // dominated by replica 0
+	}
+
+	got := FindMostAdvancedReplicationPosition(positions)
+	if got == 2 {
+		t.Fatalf("FindMostAdvancedReplicationPosition = 2, but replica 2 is strictly dominated by replica 0")
+	}
+	if positions[got].IsZero() {
+		t.Fatalf("FindMostAdvancedReplicationPosition returned a zero-value position")
+	}
+	for i := range positions {
+		if i != got && positions[i].Contains(positions[got]) && !positions[got].Contains(positions[i]) {
+			t.Errorf("replica %d strictly dominates the chosen replica %d", i, got)
+		}
+	}
+}
+
+func TestFindMostAdvancedReplicationPositionHandlesZeroValue(t *testing.T) {
+	positions := []proto.ReplicationPosition{
+		{},
+		mustMariadbPosition(t, "0-1-10"),
+	}
+
+	got := FindMostAdvancedReplicationPosition(positions)
+	if got != 1 {
+		t.Errorf("FindMostAdvancedReplicationPosition = %d, want 1 (zero-value position must not panic or win)", got)
+	}
+}
+
+// fakeEmergencyReparentFlavor implements MysqlFlavor just enough to drive
+// EmergencyReparentShard: SlaveStatus returns a canned position per *Mysqld,
+// and PromoteSlaveCommands returns a fixed command list. The rest of the
+// interface is unused by EmergencyReparentShard and panics if called.
+type fakeEmergencyReparentFlavor struct {
+	statusByMysqld map[*Mysqld]*proto.ReplicationStatus
+	promoteCmds    []string
+}
+
+func (f *fakeEmergencyReparentFlavor) SlaveStatus(mysqld *Mysqld) (*proto.ReplicationStatus, error) {
+	return f.statusByMysqld[mysqld], nil
+}
+func (f *fakeEmergencyReparentFlavor) PromoteSlaveCommands() []string { return f.promoteCmds }
+
+func (f *fakeEmergencyReparentFlavor) MasterPosition(mysqld *Mysqld) (proto.ReplicationPosition, error) {
+	panic("not used by EmergencyReparentShard")
+}
+func (f *fakeEmergencyReparentFlavor) StartReplicationCommands(params *mysql.ConnectionParams, status *proto.ReplicationStatus) ([]string, error) {
+	panic("not used by EmergencyReparentShard")
+}
+func (f *fakeEmergencyReparentFlavor) ParseGTID(string) (proto.GTID, error) {
+	panic("not used by EmergencyReparentShard")
+}
+func (f *fakeEmergencyReparentFlavor) ParseReplicationPosition(string) (proto.ReplicationPosition, error) {
+	panic("not used by EmergencyReparentShard")
+}
+func (f *fakeEmergencyReparentFlavor) SendBinlogDumpCommand(mysqld *Mysqld, conn *SlaveConnection, startPos proto.ReplicationPosition) error {
+	panic("not used by EmergencyReparentShard")
+}
+func (f *fakeEmergencyReparentFlavor) MakeBinlogEvent(buf []byte) blproto.BinlogEvent {
+	panic("not used by EmergencyReparentShard")
+}
+func (f *fakeEmergencyReparentFlavor) WaitMasterPos(mysqld *Mysqld, targetPos proto.ReplicationPosition, waitTimeout time.Duration) error {
+	panic("not used by EmergencyReparentShard")
+}
+func (f *fakeEmergencyReparentFlavor) ChecksumAlgorithm() string {
+	panic("not used by EmergencyReparentShard")
+}
+
+func TestEmergencyReparentShardPicksMostAdvanced(t *testing.T) {
+	behind := &Mysqld{}
+	ahead := &Mysqld{}
+	flavor := &fakeEmergencyReparentFlavor{
+		statusByMysqld: map[*Mysqld]*proto.ReplicationStatus{
+			behind: {Position: mustMariadbPosition(t, "0-1-5")},
+			ahead:  {Position: mustMariadbPosition(t, "0-1-10")},
+		},
+		promoteCmds: []string{"STOP SLAVE", "RESET MASTER"},
+	}
+
+	index, cmds, err := EmergencyReparentShard(flavor, []*Mysqld{behind, ahead})
+	if err != nil {
+		t.Fatalf("EmergencyReparentShard: %v", err)
+	}
+	if index != 1 {
+		t.Errorf("EmergencyReparentShard index = %d, want 1 (the more advanced candidate)", index)
+	}
+	if len(cmds) != 2 || cmds[0] != "STOP SLAVE" {
+		t.Errorf("EmergencyReparentShard commands = %v, want flavor.PromoteSlaveCommands()", cmds)
+	}
+}
+
+func TestEmergencyReparentShardNoCandidates(t *testing.T) {
+	flavor := &fakeEmergencyReparentFlavor{}
+	if _, _, err := EmergencyReparentShard(flavor, nil); err == nil {
+		t.Error("EmergencyReparentShard with no candidates succeeded, want an error")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"flag"
+
+	"github.com/youtube/vitess/go/mysql"
+)
+
+var (
+	replicationSslCa   = flag.String("replication_ssl_ca", "", "path to a PEM CA certificate used to verify the replication master; setting it enables SSL for replication connections")
+	replicationSslCert = flag.String("replication_ssl_cert", "", "path to a PEM client certificate used to authenticate to the replication master")
+	replicationSslKey  = flag.String("replication_ssl_key", "", "path to the PEM private key matching replication_ssl_cert")
+)
+
+// ApplyReplicationSSLFlags copies the --replication_ssl_ca/_cert/_key flags
+// into params, enabling SSL if a CA was provided. This lets mysqlctl start
+// replication across untrusted networks without requiring every caller to
+// thread the flags through by hand.
+func ApplyReplicationSSLFlags(params *mysql.ConnectionParams) {
+	if *replicationSslCa == "" {
+		return
+	}
+	params.SslEnabled = true
+	params.SslCa = *replicationSslCa
+	params.SslCert = *replicationSslCert
+	params.SslKey = *replicationSslKey
+	params.SslVerifyServerCert = true
+}
@@ -0,0 +1,142 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+
+	"github.com/youtube/vitess/go/mysql"
+	"github.com/youtube/vitess/go/sqltypes"
+)
+
+// checksumSize is the length in bytes of the CRC32 checksum MySQL appends
+// to every binlog event when binlog_checksum=CRC32.
+const checksumSize = 4
+
+// SlaveConnection is a dedicated connection that has registered itself as a
+// replication slave with a master, and can be used to issue COM_BINLOG_DUMP
+// and read the resulting stream of raw binlog event packets.
+type SlaveConnection struct {
+	conn    *mysql.Conn
+	slaveID uint32
+
+	// checksumAlgorithm is the binlog checksum algorithm negotiated with
+	// the master during slave registration ("NONE" or "CRC32"). It starts
+	// as "NONE" because that's what a master assumes until told
+	// otherwise, and only becomes "CRC32" once negotiateChecksum has
+	// actually run; ReadPacket uses it to decide whether to strip and
+	// verify a trailing CRC32 on every event.
+	checksumAlgorithm string
+}
+
+// NewSlaveConnection opens a new connection to mysqld.Params and registers it
+// as a replication slave under serverID. If serverID is 0, a random one is
+// used instead, since the master only needs it to be unique among slaves.
+//
+// It dials through the same mysql.ConnectionParams.DialOptions() path as
+// Mysqld.ExecuteFetch, so admin queries and the replication stream negotiate
+// TLS identically: if mysqld.Params has SSL enabled, the connection
+// negotiates TLS via the CLIENT_SSL capability flag before issuing any
+// replication commands, using the CA/cert/key configured by
+// --replication_ssl_ca, --replication_ssl_cert and --replication_ssl_key.
+func NewSlaveConnection(mysqld *Mysqld, serverID uint32) (*SlaveConnection, error) {
+	params := mysqld.Params
+	conn, err := mysql.DialWithOptions(params, params.DialOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to MySQL to start replication: %v", err)
+	}
+
+	if serverID == 0 {
+		serverID = rand.Uint32()
+	}
+	return &SlaveConnection{
+		conn:              conn,
+		slaveID:           serverID,
+		checksumAlgorithm: "NONE",
+	}, nil
+}
+
+// EnableSemiSync tells the master this slave wants semi-sync acknowledgement
+// of each event it sends.
+func (sc *SlaveConnection) EnableSemiSync() error {
+	if _, err := sc.conn.ExecuteFetch("SET @rpl_semi_sync_slave=1", 0, false); err != nil {
+		return fmt.Errorf("failed to set @rpl_semi_sync_slave: %v", err)
+	}
+	return nil
+}
+
+// negotiateChecksum tells the master which binlog checksum algorithm this
+// connection expects, and remembers it so ReadPacket knows whether (and how)
+// to verify each event's trailing checksum.
+func (sc *SlaveConnection) negotiateChecksum(algorithm string) error {
+	if _, err := sc.conn.ExecuteFetch(fmt.Sprintf("SET @master_binlog_checksum='%s'", algorithm), 0, false); err != nil {
+		return fmt.Errorf("failed to set @master_binlog_checksum: %v", err)
+	}
+	sc.checksumAlgorithm = algorithm
+	return nil
+}
+
+// DisableChecksum turns off checksum verification on this connection,
+// without re-negotiating with the master. This is meant for debugging
+// against masters whose checksum support doesn't match what they report,
+// not for normal operation.
+func (sc *SlaveConnection) DisableChecksum() {
+	sc.checksumAlgorithm = "NONE"
+}
+
+// ExecuteFetch runs a registration/admin query (e.g. the SET @... commands
+// issued before COM_BINLOG_DUMP) on this connection.
+func (sc *SlaveConnection) ExecuteFetch(query string, maxrows int, wantFields bool) (*sqltypes.Result, error) {
+	return sc.conn.ExecuteFetch(query, maxrows, wantFields)
+}
+
+// WriteComBinlogDump sends the standard (non-GTID) COM_BINLOG_DUMP command,
+// asking the master to start streaming from the given file/position.
+func (sc *SlaveConnection) WriteComBinlogDump(serverID uint32, filename string, position uint32, flags uint16) error {
+	return sc.conn.WriteComBinlogDump(serverID, filename, position, flags)
+}
+
+// ReadPacket blocks until the next raw binlog event packet arrives, then
+// strips and (if CRC32 checksums are in use) verifies its trailing
+// checksum before returning it.
+func (sc *SlaveConnection) ReadPacket() ([]byte, error) {
+	buf, err := sc.conn.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	return stripChecksum(buf, sc.checksumAlgorithm)
+}
+
+// stripChecksum strips and verifies a packet's trailing CRC32 checksum when
+// algorithm is "CRC32", or returns buf unchanged otherwise (e.g. algorithm
+// is "NONE", which is what every connection starts as until
+// negotiateChecksum runs). Split out from ReadPacket so it can be tested
+// without a live connection.
+func stripChecksum(buf []byte, algorithm string) ([]byte, error) {
+	if algorithm != "CRC32" {
+		return buf, nil
+	}
+
+	if len(buf) < checksumSize {
+		return nil, fmt.Errorf("binlog event packet too short to hold a CRC32 checksum: %v bytes", len(buf))
+	}
+	split := len(buf) - checksumSize
+	event, checksum := buf[:split], buf[split:]
+
+	want := binary.LittleEndian.Uint32(checksum)
+	got := crc32.ChecksumIEEE(event)
+	if want != got {
+		return nil, fmt.Errorf("binlog event checksum mismatch: got %08x, want %08x", got, want)
+	}
+	return event, nil
+}
+
+// Close releases the underlying connection.
+func (sc *SlaveConnection) Close() {
+	sc.conn.Close()
+}
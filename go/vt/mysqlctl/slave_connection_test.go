@@ -0,0 +1,52 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlctl
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func TestStripChecksumNoneReturnsBufUnchanged(t *testing.T) {
+	buf := []byte{1, 2, 3, 4, 5}
+	got, err := stripChecksum(buf, "NONE")
+	if err != nil {
+		t.Fatalf("stripChecksum: %v", err)
+	}
+	if len(got) != len(buf) {
+		t.Errorf("stripChecksum(%v, NONE) = %v, want unchanged", buf, got)
+	}
+}
+
+func TestStripChecksumCRC32StripsAndVerifies(t *testing.T) {
+	event := []byte{10, 20, 30, 40, 50}
+	checksum := make([]byte, 4)
+	binary.LittleEndian.PutUint32(checksum, crc32.ChecksumIEEE(event))
+	buf := append(append([]byte{}, event...), checksum...)
+
+	got, err := stripChecksum(buf, "CRC32")
+	if err != nil {
+		t.Fatalf("stripChecksum: %v", err)
+	}
+	if string(got) != string(event) {
+		t.Errorf("stripChecksum(%v, CRC32) = %v, want %v", buf, got, event)
+	}
+}
+
+func TestStripChecksumCRC32DetectsMismatch(t *testing.T) {
+	event := []byte{10, 20, 30, 40, 50}
+	buf := append(append([]byte{}, event...), 0xDE, 0xAD, 0xBE, 0xEF)
+
+	if _, err := stripChecksum(buf, "CRC32"); err == nil {
+		t.Error("stripChecksum with a bad checksum succeeded, want an error")
+	}
+}
+
+func TestStripChecksumCRC32TooShort(t *testing.T) {
+	if _, err := stripChecksum([]byte{1, 2, 3}, "CRC32"); err == nil {
+		t.Error("stripChecksum with a too-short buffer succeeded, want an error")
+	}
+}